@@ -18,10 +18,18 @@ package nodeorder
 
 import (
 	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/golang/glog"
 
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/kubernetes/pkg/scheduler/algorithm/priorities"
 	schedulerapi "k8s.io/kubernetes/pkg/scheduler/api"
 	"k8s.io/kubernetes/pkg/scheduler/cache"
@@ -40,6 +48,19 @@ const (
 	LeastRequestedWeight = "leastrequested.weight"
 	// BalancedResourceWeight is the key for providing Balanced Resource Priority Weight in YAML
 	BalancedResourceWeight = "balancedresource.weight"
+	// ImageLocalityWeight is the key for providing Image Locality Priority Weight in YAML
+	ImageLocalityWeight = "imagelocality.weight"
+	// RequestedToCapacityRatioWeight is the key for providing Requested To Capacity Ratio Priority Weight in YAML
+	RequestedToCapacityRatioWeight = "requestedtocapacityratio.weight"
+	// RequestedToCapacityRatioResources is the key for providing the per-resource weights used by the
+	// Requested To Capacity Ratio Priority in YAML, e.g. "cpu=1,memory=1,nvidia.com/gpu=2"
+	RequestedToCapacityRatioResources = "requestedtocapacityratio.resources"
+	// RequestedToCapacityRatioShape is the key for providing the utilization->score shape used by the
+	// Requested To Capacity Ratio Priority in YAML, e.g. "0:0,100:10" for bin-packing or "0:10,100:0" for spreading
+	RequestedToCapacityRatioShape = "requestedtocapacityratio.shape"
+	// EvenPodsSpreadWeight is the key for providing Even Pods Spread Priority Weight in YAML. This
+	// priority scores nodes against the task pod's own TopologySpreadConstraints.
+	EvenPodsSpreadWeight = "evenpodsspread.weight"
 )
 
 type nodeOrderPlugin struct {
@@ -86,65 +107,898 @@ func (pp *nodeOrderPlugin) Name() string {
 	return "nodeorder"
 }
 
-type priorityWeight struct {
-	leastReqWeight          int
-	nodeAffinityWeight      int
-	podAffinityWeight       int
-	balancedRescourceWeight int
+// functionShapePoint is a single (utilization, score) breakpoint used to interpolate the
+// RequestedToCapacityRatio scoring curve. Utilization is a percentage in [0, 100], score is in [0, 10].
+type functionShapePoint struct {
+	utilization int64
+	score       int64
 }
 
-func calculateWeight(args framework.Arguments) priorityWeight {
-	/*
-	   User Should give priorityWeight in this format(nodeaffinity.weight, podaffinity.weight, leastrequested.weight, balancedresource.weight).
-	   Currently supported only for nodeaffinity, podaffinity, leastrequested, balancedresouce priorities.
+// functionShape is a monotonically increasing (in utilization) list of functionShapePoints.
+type functionShape []functionShapePoint
 
-	   actions: "reclaim, allocate, backfill, preempt"
-	   tiers:
-	   - plugins:
-	     - name: priority
-	     - name: gang
-	     - name: conformance
-	   - plugins:
-	     - name: drf
-	     - name: predicates
-	     - name: proportion
-	     - name: nodeorder
-	       arguments:
-	         nodeaffinity.weight: 2
-	         podaffinity.weight: 2
-	         leastrequested.weight: 2
-	         balancedresource.weight: 2
-	*/
+// defaultRequestedToCapacityRatioShape mirrors LeastRequested: prefer nodes with lower utilization.
+var defaultRequestedToCapacityRatioShape = functionShape{
+	{utilization: 0, score: 10},
+	{utilization: 100, score: 0},
+}
+
+// defaultRequestedToCapacityRatioResources is used when requestedtocapacityratio.resources is not set.
+var defaultRequestedToCapacityRatioResources = resourceToWeightMap{
+	v1.ResourceCPU:    1,
+	v1.ResourceMemory: 1,
+}
+
+// resourceToWeightMap maps a resource name to the weight it contributes to the aggregated score.
+type resourceToWeightMap map[v1.ResourceName]int64
+
+// score returns the interpolated score for the given utilization percentage, clamping to the
+// shape's first/last breakpoints for out-of-range values.
+func (f functionShape) score(utilization int64) int64 {
+	if utilization <= f[0].utilization {
+		return f[0].score
+	}
+	for i := 1; i < len(f); i++ {
+		if utilization <= f[i].utilization {
+			prev, cur := f[i-1], f[i]
+			if cur.utilization == prev.utilization {
+				return cur.score
+			}
+			return prev.score + (cur.score-prev.score)*(utilization-prev.utilization)/(cur.utilization-prev.utilization)
+		}
+	}
+	return f[len(f)-1].score
+}
+
+// parseResourceToWeightMap parses a "<resource>=<weight>[,<resource>=<weight>...]" argument.
+func parseResourceToWeightMap(raw string) (resourceToWeightMap, error) {
+	resources := resourceToWeightMap{}
+	if raw == "" {
+		return resources, nil
+	}
+
+	for _, entry := range strings.Split(raw, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid %s entry %q, expected <resource>=<weight>", RequestedToCapacityRatioResources, entry)
+		}
+
+		weight, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight for resource %q: %v", parts[0], err)
+		}
+
+		resources[v1.ResourceName(strings.TrimSpace(parts[0]))] = weight
+	}
+
+	return resources, nil
+}
+
+// parseFunctionShape parses a "<utilization>:<score>[,<utilization>:<score>...]" argument and
+// validates that it is monotonically increasing in utilization and non-negative in score.
+func parseFunctionShape(raw string) (functionShape, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	var shape functionShape
+	for _, entry := range strings.Split(raw, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid %s entry %q, expected <utilization>:<score>", RequestedToCapacityRatioShape, entry)
+		}
+
+		utilization, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid utilization in shape point %q: %v", entry, err)
+		}
+		score, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid score in shape point %q: %v", entry, err)
+		}
+		if utilization < 0 || utilization > 100 {
+			return nil, fmt.Errorf("utilization %d in shape point %q must be within [0, 100]", utilization, entry)
+		}
+		if score < 0 || score > 10 {
+			return nil, fmt.Errorf("score %d in shape point %q must be within [0, 10]", score, entry)
+		}
+
+		shape = append(shape, functionShapePoint{utilization: utilization, score: score})
+	}
+
+	for i := 1; i < len(shape); i++ {
+		if shape[i].utilization <= shape[i-1].utilization {
+			return nil, fmt.Errorf("utilization values in %s must be strictly increasing", RequestedToCapacityRatioShape)
+		}
+	}
+
+	return shape, nil
+}
+
+// requestedResourceValue returns the amount of resourceName requested by pod, summed across containers.
+func requestedResourceValue(pod *v1.Pod, resourceName v1.ResourceName) int64 {
+	var value int64
+	for _, container := range pod.Spec.Containers {
+		quantity, ok := container.Resources.Requests[resourceName]
+		if !ok {
+			continue
+		}
+		if resourceName == v1.ResourceCPU {
+			value += quantity.MilliValue()
+		} else {
+			value += quantity.Value()
+		}
+	}
+	return value
+}
+
+// allocatableResourceValue returns the node's allocatable amount of resourceName.
+func allocatableResourceValue(nodeInfo *cache.NodeInfo, resourceName v1.ResourceName) int64 {
+	allocatable := nodeInfo.AllocatableResource()
+	switch resourceName {
+	case v1.ResourceCPU:
+		return allocatable.MilliCPU
+	case v1.ResourceMemory:
+		return allocatable.Memory
+	case v1.ResourceEphemeralStorage:
+		return allocatable.EphemeralStorage
+	default:
+		if allocatable.ScalarResources != nil {
+			return allocatable.ScalarResources[resourceName]
+		}
+		return 0
+	}
+}
+
+// requestedPlusNodeResourceValue returns the node's already-requested amount of resourceName plus
+// what task additionally requests. podRequests, if non-nil, is consulted first so callers that
+// already precomputed a task's cpu/memory requests (see PriorityMetadata) don't re-walk its
+// containers for every candidate node; any resourceName missing from it is still computed from pod.
+func requestedPlusNodeResourceValue(nodeInfo *cache.NodeInfo, pod *v1.Pod, podRequests map[v1.ResourceName]int64, resourceName v1.ResourceName) int64 {
+	requested := nodeInfo.RequestedResource()
+	var nodeRequested int64
+	switch resourceName {
+	case v1.ResourceCPU:
+		nodeRequested = requested.MilliCPU
+	case v1.ResourceMemory:
+		nodeRequested = requested.Memory
+	case v1.ResourceEphemeralStorage:
+		nodeRequested = requested.EphemeralStorage
+	default:
+		if requested.ScalarResources != nil {
+			nodeRequested = requested.ScalarResources[resourceName]
+		}
+	}
+
+	if podRequest, ok := podRequests[resourceName]; ok {
+		return nodeRequested + podRequest
+	}
+	return nodeRequested + requestedResourceValue(pod, resourceName)
+}
+
+// requestedToCapacityRatioScore computes the weighted-average shape score across the configured
+// resources for a single node, skipping resources whose allocatable amount is zero. podRequests is
+// passed straight through to requestedPlusNodeResourceValue and may be nil.
+func requestedToCapacityRatioScore(task *api.TaskInfo, nodeInfo *cache.NodeInfo, podRequests map[v1.ResourceName]int64, resources resourceToWeightMap, shape functionShape) float64 {
+	var totalScore, totalWeight int64
+	for resourceName, resourceWeight := range resources {
+		allocatable := allocatableResourceValue(nodeInfo, resourceName)
+		if allocatable == 0 {
+			continue
+		}
+
+		utilization := 100 * requestedPlusNodeResourceValue(nodeInfo, task.Pod, podRequests, resourceName) / allocatable
+		if utilization > 100 {
+			utilization = 100
+		}
+
+		totalScore += shape.score(utilization) * resourceWeight
+		totalWeight += resourceWeight
+	}
+
+	if totalWeight == 0 {
+		return 0
+	}
+
+	return float64(totalScore) / float64(totalWeight)
+}
+
+const (
+	// imageLocalityMinThreshold is the scaled image size below which a node gets no ImageLocality score.
+	imageLocalityMinThreshold = 23 * 1024 * 1024
+	// imageLocalityMaxThreshold is the scaled image size at/above which a node gets the max ImageLocality score.
+	imageLocalityMaxThreshold = 1000 * 1024 * 1024
+)
+
+// nodeImageSize returns the size and presence of image among the images already cached on node.
+func nodeImageSize(node *v1.Node, image string) (int64, bool) {
+	for _, containerImage := range node.Status.Images {
+		for _, name := range containerImage.Names {
+			if name == image {
+				return containerImage.SizeBytes, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// containerImages returns the image reference of every container in pod's spec.
+func containerImages(pod *v1.Pod) []string {
+	images := make([]string, 0, len(pod.Spec.Containers))
+	for _, container := range pod.Spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+// imageNodeCounts returns, for every image name advertised by any node's Status.Images, the number of
+// nodeSlice nodes that have it cached. It is cluster-wide and does not depend on which node or pod is
+// being scored, so callers compute it once per task rather than once per (image, candidate node) pair.
+func imageNodeCounts(nodeSlice []*v1.Node) map[string]int {
+	counts := make(map[string]int)
+	for _, node := range nodeSlice {
+		seen := make(map[string]bool)
+		for _, containerImage := range node.Status.Images {
+			for _, name := range containerImage.Names {
+				if !seen[name] {
+					seen[name] = true
+					counts[name]++
+				}
+			}
+		}
+	}
+	return counts
+}
+
+// imageLocalitySpread sums, over the pod's container images that are cached on node, the image size
+// scaled by the fraction of cluster nodes that also have it. Scaling keeps a handful of giant, rarely
+// shared images from dominating the score of a cluster that mostly shares small common layers.
+// nodeCounts is the cluster-wide per-image node count from imageNodeCounts, and totalNodes its node count.
+func imageLocalitySpread(images []string, node *v1.Node, nodeCounts map[string]int, totalNodes int) int64 {
+	if totalNodes == 0 {
+		return 0
+	}
+
+	var spread int64
+	for _, image := range images {
+		size, found := nodeImageSize(node, image)
+		if !found {
+			continue
+		}
+
+		fraction := float64(nodeCounts[image]) / float64(totalNodes)
+		spread += int64(float64(size) * fraction)
+	}
+
+	return spread
+}
+
+// imageLocalityHostScore maps a scaled image size to a 0..10 score, linearly interpolating between
+// imageLocalityMinThreshold and imageLocalityMaxThreshold.
+func imageLocalityHostScore(spread int64) int64 {
+	if spread < imageLocalityMinThreshold {
+		return 0
+	}
+	if spread > imageLocalityMaxThreshold {
+		return 10
+	}
+	return 10 * (spread - imageLocalityMinThreshold) / (imageLocalityMaxThreshold - imageLocalityMinThreshold)
+}
+
+// ScorerContext bundles the per-session state a NodeScorer factory may need: the plugin's own
+// arguments plus the node/pod listers and cache.NodeInfo map nodeorder already maintains for the
+// upstream priorities package. Most scorers only touch a couple of these fields.
+type ScorerContext struct {
+	Arguments      framework.Arguments
+	NodeMap        map[string]*cache.NodeInfo
+	NodeSlice      []*v1.Node
+	PodLister      *util.PodLister
+	NodeLister     *util.NodeLister
+	CachedNodeInfo *cachedNodeInfo
+}
+
+// NodeScorer is implemented by each scoring function nodeorder runs for a (task, node) pair. It
+// mirrors the ScorePlugin extension point from the upstream kube-scheduler framework, giving
+// out-of-tree code a place to plug in custom scorers (topology awareness, GPU locality, ...) without
+// touching nodeOrderFn itself.
+type NodeScorer interface {
+	// Name is this scorer's identifier. It also doubles as the prefix of its "<name>.weight" argument.
+	Name() string
+	// Score returns the raw, pre-weight score of scheduling task onto node.
+	Score(task *api.TaskInfo, node *api.NodeInfo) (int, error)
+	// NormalizeScores is handed every candidate node's raw score for the current task, keyed by node
+	// name, once all Score calls for that task have completed, so scorers whose score only makes sense
+	// relative to the rest of the cluster can rescale it in place. Scorers that don't need this can
+	// no-op. batchScorer calls this once per task, after scoring every node and before applying weights.
+	NormalizeScores(scores map[string]int) error
+}
+
+// NodeScorerFactory builds a NodeScorer from the current session's scoring context.
+type NodeScorerFactory func(ctx *ScorerContext) NodeScorer
+
+var nodeScorerFactories = map[string]NodeScorerFactory{}
+
+// RegisterNodeScorer registers a NodeScorer factory under name, so every nodeorder session picks it
+// up automatically. Call this from an init() function; it is how out-of-tree scorers plug in without
+// modifying this package.
+func RegisterNodeScorer(name string, factory NodeScorerFactory) {
+	nodeScorerFactories[name] = factory
+}
+
+func init() {
+	RegisterNodeScorer("leastrequested", newLeastRequestedScorer)
+	RegisterNodeScorer("balancedresource", newBalancedResourceScorer)
+	RegisterNodeScorer("nodeaffinity", newNodeAffinityScorer)
+	RegisterNodeScorer("podaffinity", newPodAffinityScorer)
+	RegisterNodeScorer("imagelocality", newImageLocalityScorer)
+	RegisterNodeScorer("requestedtocapacityratio", newRequestedToCapacityRatioScorer)
+	RegisterNodeScorer("evenpodsspread", newEvenPodsSpreadScorer)
+}
+
+// defaultNodeScorerWeight is the weight a built-in scorer uses when its "<name>.weight" argument
+// isn't set.
+func defaultNodeScorerWeight(name string) int {
+	if name == "requestedtocapacityratio" {
+		// Opt-in: contributes nothing unless requestedtocapacityratio.weight is set. Without
+		// resources/shape arguments it scores cpu and memory with the LeastRequested-like default shape.
+		return 0
+	}
+	return 1
+}
+
+// builtinNodeScorerWeightArgs maps each built-in scorer's name to its exported "<name>.weight"
+// argument key constant, so the constant stays the single source of truth for that string.
+var builtinNodeScorerWeightArgs = map[string]string{
+	"nodeaffinity":             NodeAffinityWeight,
+	"podaffinity":              PodAffinityWeight,
+	"leastrequested":           LeastRequestedWeight,
+	"balancedresource":         BalancedResourceWeight,
+	"imagelocality":            ImageLocalityWeight,
+	"requestedtocapacityratio": RequestedToCapacityRatioWeight,
+	"evenpodsspread":           EvenPodsSpreadWeight,
+}
+
+// nodeScorerWeightArg returns the plugin argument key a scorer's weight is read from: the exported
+// constant for built-in scorers, or "<name>.weight" for third-party scorers registered via
+// RegisterNodeScorer.
+func nodeScorerWeightArg(name string) string {
+	if key, ok := builtinNodeScorerWeightArgs[name]; ok {
+		return key
+	}
+	return name + ".weight"
+}
+
+// resolveNodeInfo returns the cached cache.NodeInfo for node, falling back to building one from the
+// node's current pods if the session's nodeMap doesn't have it yet.
+func resolveNodeInfo(nodeMap map[string]*cache.NodeInfo, node *api.NodeInfo) *cache.NodeInfo {
+	if nodeInfo, found := nodeMap[node.Name]; found {
+		return nodeInfo
+	}
+	glog.Warningf("node order, generate node info for %s at NodeOrderFn is unexpected", node.Name)
+	nodeInfo := cache.NewNodeInfo(node.Pods()...)
+	nodeInfo.SetNode(node.Node)
+	return nodeInfo
+}
+
+type leastRequestedScorer struct {
+	nodeMap map[string]*cache.NodeInfo
+}
+
+func newLeastRequestedScorer(ctx *ScorerContext) NodeScorer {
+	return &leastRequestedScorer{nodeMap: ctx.NodeMap}
+}
+
+func (s *leastRequestedScorer) Name() string { return "leastrequested" }
+
+func (s *leastRequestedScorer) Score(task *api.TaskInfo, node *api.NodeInfo) (int, error) {
+	host, err := priorities.LeastRequestedPriorityMap(task.Pod, nil, resolveNodeInfo(s.nodeMap, node))
+	if err != nil {
+		return 0, fmt.Errorf("least requested priority failed: %v", err)
+	}
+	return host.Score, nil
+}
+
+func (s *leastRequestedScorer) NormalizeScores(map[string]int) error { return nil }
+
+type balancedResourceScorer struct {
+	nodeMap map[string]*cache.NodeInfo
+}
+
+func newBalancedResourceScorer(ctx *ScorerContext) NodeScorer {
+	return &balancedResourceScorer{nodeMap: ctx.NodeMap}
+}
+
+func (s *balancedResourceScorer) Name() string { return "balancedresource" }
+
+func (s *balancedResourceScorer) Score(task *api.TaskInfo, node *api.NodeInfo) (int, error) {
+	host, err := priorities.BalancedResourceAllocationMap(task.Pod, nil, resolveNodeInfo(s.nodeMap, node))
+	if err != nil {
+		return 0, fmt.Errorf("balanced resource allocation priority failed: %v", err)
+	}
+	return host.Score, nil
+}
+
+func (s *balancedResourceScorer) NormalizeScores(map[string]int) error { return nil }
+
+type nodeAffinityScorer struct {
+	nodeMap map[string]*cache.NodeInfo
+}
+
+func newNodeAffinityScorer(ctx *ScorerContext) NodeScorer {
+	return &nodeAffinityScorer{nodeMap: ctx.NodeMap}
+}
+
+func (s *nodeAffinityScorer) Name() string { return "nodeaffinity" }
+
+func (s *nodeAffinityScorer) Score(task *api.TaskInfo, node *api.NodeInfo) (int, error) {
+	host, err := priorities.CalculateNodeAffinityPriorityMap(task.Pod, nil, resolveNodeInfo(s.nodeMap, node))
+	if err != nil {
+		return 0, fmt.Errorf("calculate node affinity priority failed: %v", err)
+	}
+	return host.Score, nil
+}
+
+func (s *nodeAffinityScorer) NormalizeScores(map[string]int) error { return nil }
+
+type podAffinityScorer struct {
+	nodeMap        map[string]*cache.NodeInfo
+	nodeSlice      []*v1.Node
+	cachedNodeInfo *cachedNodeInfo
+	nodeLister     *util.NodeLister
+	podLister      *util.PodLister
+}
+
+func newPodAffinityScorer(ctx *ScorerContext) NodeScorer {
+	return &podAffinityScorer{
+		nodeMap:        ctx.NodeMap,
+		nodeSlice:      ctx.NodeSlice,
+		cachedNodeInfo: ctx.CachedNodeInfo,
+		nodeLister:     ctx.NodeLister,
+		podLister:      ctx.PodLister,
+	}
+}
+
+func (s *podAffinityScorer) Name() string { return "podaffinity" }
+
+func (s *podAffinityScorer) Score(task *api.TaskInfo, node *api.NodeInfo) (int, error) {
+	scores, err := interPodAffinityScores(task, s.cachedNodeInfo, s.nodeLister, s.podLister, s.nodeMap, s.nodeSlice)
+	if err != nil {
+		return 0, err
+	}
+	return getInterPodAffinityScore(node.Name, scores), nil
+}
+
+// ScoreWithMetadata looks the node up in meta's InterPodAffinity result, which batchScorer computes
+// once per task instead of once per (task, node) pair.
+func (s *podAffinityScorer) ScoreWithMetadata(task *api.TaskInfo, node *api.NodeInfo, meta *PriorityMetadata) (int, error) {
+	return getInterPodAffinityScore(node.Name, meta.PodAffinityScores), nil
+}
 
-	// Values are initialized to 1.
-	weight := priorityWeight{
-		leastReqWeight:          1,
-		nodeAffinityWeight:      1,
-		podAffinityWeight:       1,
-		balancedRescourceWeight: 1,
+func (s *podAffinityScorer) NormalizeScores(map[string]int) error { return nil }
+
+// interPodAffinityScores runs the upstream InterPodAffinity priority once across every node in
+// nodeSlice. It exists so both podAffinityScorer.Score (the per-node path) and PriorityMetadata (the
+// once-per-task batch path) share a single implementation.
+func interPodAffinityScores(task *api.TaskInfo, cn *cachedNodeInfo, nl *util.NodeLister, pl *util.PodLister, nodeMap map[string]*cache.NodeInfo, nodeSlice []*v1.Node) (schedulerapi.HostPriorityList, error) {
+	mapFn := priorities.NewInterPodAffinityPriority(cn, nl, pl, v1.DefaultHardPodAffinitySymmetricWeight)
+	scores, err := mapFn(task.Pod, nodeMap, nodeSlice)
+	if err != nil {
+		return nil, fmt.Errorf("calculate inter pod affinity priority failed: %v", err)
 	}
+	return scores, nil
+}
+
+type imageLocalityScorer struct {
+	nodeSlice []*v1.Node
+}
+
+func newImageLocalityScorer(ctx *ScorerContext) NodeScorer {
+	return &imageLocalityScorer{nodeSlice: ctx.NodeSlice}
+}
+
+func (s *imageLocalityScorer) Name() string { return "imagelocality" }
+
+func (s *imageLocalityScorer) Score(task *api.TaskInfo, node *api.NodeInfo) (int, error) {
+	counts := imageNodeCounts(s.nodeSlice)
+	spread := imageLocalitySpread(containerImages(task.Pod), node.Node, counts, len(s.nodeSlice))
+	return int(imageLocalityHostScore(spread)), nil
+}
+
+// ScoreWithMetadata reuses the container image list and cluster-wide per-image node counts already
+// collected once per task in meta, instead of re-walking task.Pod.Spec.Containers and rescanning every
+// node in the cluster for every candidate node.
+func (s *imageLocalityScorer) ScoreWithMetadata(task *api.TaskInfo, node *api.NodeInfo, meta *PriorityMetadata) (int, error) {
+	spread := imageLocalitySpread(meta.ContainerImages, node.Node, meta.ImageNodeCounts, meta.TotalNodes)
+	return int(imageLocalityHostScore(spread)), nil
+}
+
+func (s *imageLocalityScorer) NormalizeScores(map[string]int) error { return nil }
 
-	// Checks whether nodeaffinity.weight is provided or not, if given, modifies the value in weight struct.
-	args.GetInt(&weight.nodeAffinityWeight, NodeAffinityWeight)
+type requestedToCapacityRatioScorer struct {
+	nodeMap   map[string]*cache.NodeInfo
+	resources resourceToWeightMap
+	shape     functionShape
+}
 
-	// Checks whether podaffinity.weight is provided or not, if given, modifies the value in weight struct.
-	args.GetInt(&weight.podAffinityWeight, PodAffinityWeight)
+func newRequestedToCapacityRatioScorer(ctx *ScorerContext) NodeScorer {
+	resources := defaultRequestedToCapacityRatioResources
+	if raw, ok := ctx.Arguments[RequestedToCapacityRatioResources]; ok {
+		parsed, err := parseResourceToWeightMap(fmt.Sprintf("%v", raw))
+		if err != nil {
+			glog.Errorf("Invalid %s: %v, falling back to defaults", RequestedToCapacityRatioResources, err)
+		} else {
+			resources = parsed
+		}
+	}
 
-	// Checks whether leastrequested.weight is provided or not, if given, modifies the value in weight struct.
-	args.GetInt(&weight.leastReqWeight, LeastRequestedWeight)
+	shape := defaultRequestedToCapacityRatioShape
+	if raw, ok := ctx.Arguments[RequestedToCapacityRatioShape]; ok {
+		parsed, err := parseFunctionShape(fmt.Sprintf("%v", raw))
+		if err != nil {
+			glog.Errorf("Invalid %s: %v, falling back to default shape", RequestedToCapacityRatioShape, err)
+		} else {
+			shape = parsed
+		}
+	}
 
-	// Checks whether balancedresource.weight is provided or not, if given, modifies the value in weight struct.
-	args.GetInt(&weight.balancedRescourceWeight, BalancedResourceWeight)
+	return &requestedToCapacityRatioScorer{nodeMap: ctx.NodeMap, resources: resources, shape: shape}
+}
+
+func (s *requestedToCapacityRatioScorer) Name() string { return "requestedtocapacityratio" }
+
+func (s *requestedToCapacityRatioScorer) Score(task *api.TaskInfo, node *api.NodeInfo) (int, error) {
+	return int(requestedToCapacityRatioScore(task, resolveNodeInfo(s.nodeMap, node), nil, s.resources, s.shape)), nil
+}
+
+func (s *requestedToCapacityRatioScorer) NormalizeScores(map[string]int) error { return nil }
+
+// ScoreWithMetadata reuses the task's precomputed cpu/memory requests from meta, falling back to
+// requestedResourceValue (when the resource is absent from podRequests) for any other configured resource, such as
+// extended resources like nvidia.com/gpu that PriorityMetadata does not precompute.
+func (s *requestedToCapacityRatioScorer) ScoreWithMetadata(task *api.TaskInfo, node *api.NodeInfo, meta *PriorityMetadata) (int, error) {
+	return int(requestedToCapacityRatioScore(task, resolveNodeInfo(s.nodeMap, node), meta.ResourceRequests, s.resources, s.shape)), nil
+}
+
+// evenPodsSpreadScorer scores nodes against the task pod's own Pod Topology Spread Constraints
+// (pod.Spec.TopologySpreadConstraints). It only ranks candidate nodes; DoNotSchedule constraints are
+// enforced by predicates, not here.
+type evenPodsSpreadScorer struct {
+	nodeMap map[string]*cache.NodeInfo
+}
+
+func newEvenPodsSpreadScorer(ctx *ScorerContext) NodeScorer {
+	return &evenPodsSpreadScorer{nodeMap: ctx.NodeMap}
+}
+
+func (s *evenPodsSpreadScorer) Name() string { return "evenpodsspread" }
+
+// topologySpreadDomainCounts returns, for every topology domain value seen among nodeMap's nodes that
+// set topologyKey, the number of existing pods matching selector that landed in that domain. It is
+// cluster-wide and does not depend on which node is being scored, so callers compute it once per
+// (task, constraint) rather than once per node.
+func topologySpreadDomainCounts(nodeMap map[string]*cache.NodeInfo, topologyKey string, selector labels.Selector) map[string]int64 {
+	counts := map[string]int64{}
+
+	for _, nodeInfo := range nodeMap {
+		node := nodeInfo.Node()
+		if node == nil {
+			continue
+		}
+		domain, ok := node.Labels[topologyKey]
+		if !ok {
+			continue
+		}
+
+		for _, pod := range nodeInfo.Pods() {
+			if selector.Matches(labels.Set(pod.Labels)) {
+				counts[domain]++
+			}
+		}
+	}
 
-	return weight
+	return counts
+}
+
+// scoreTopologySpread computes node's raw (pre-normalize) evenpodsspread score for task: the
+// MaxSkew-weighted average, across the task's constraints, of how many matching pods (including the
+// task itself) would land in node's domain. Lower is better; NormalizeScores converts this into the
+// final 0..10 scale using the true maximum across every node scored this round, so that self-placement
+// can't shift the normalization denominator differently from one candidate node to the next.
+func scoreTopologySpread(task *api.TaskInfo, node *api.NodeInfo, domainCounts []topologySpreadConstraintCounts) (int, error) {
+	constraints := task.Pod.Spec.TopologySpreadConstraints
+	if len(constraints) == 0 {
+		return 0, nil
+	}
+
+	var weightedCount, totalWeight float64
+	for i, constraint := range constraints {
+		selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+		if err != nil {
+			return 0, fmt.Errorf("invalid topology spread constraint selector: %v", err)
+		}
+
+		domain, hasDomain := node.Node.Labels[constraint.TopologyKey]
+		count := domainCounts[i].counts[domain]
+		if hasDomain && selector.Matches(labels.Set(task.Pod.Labels)) {
+			// Account for the task itself landing in this domain.
+			count++
+		}
+
+		// Weight inversely by MaxSkew: a tighter constraint (smaller MaxSkew) should dominate the
+		// aggregate more than a looser one.
+		constraintWeight := 1.0
+		if constraint.MaxSkew > 0 {
+			constraintWeight = 1.0 / float64(constraint.MaxSkew)
+		}
+
+		weightedCount += float64(count) * constraintWeight
+		totalWeight += constraintWeight
+	}
+
+	if totalWeight == 0 {
+		return 0, nil
+	}
+
+	return int(weightedCount / totalWeight), nil
+}
+
+func (s *evenPodsSpreadScorer) Score(task *api.TaskInfo, node *api.NodeInfo) (int, error) {
+	constraints := task.Pod.Spec.TopologySpreadConstraints
+	domainCounts := make([]topologySpreadConstraintCounts, len(constraints))
+	for i, constraint := range constraints {
+		selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+		if err != nil {
+			return 0, fmt.Errorf("invalid topology spread constraint selector: %v", err)
+		}
+		domainCounts[i] = topologySpreadConstraintCounts{counts: topologySpreadDomainCounts(s.nodeMap, constraint.TopologyKey, selector)}
+	}
+
+	return scoreTopologySpread(task, node, domainCounts)
+}
+
+// ScoreWithMetadata reuses the per-constraint domain counts computed once per task in meta, instead of
+// recomputing them - a cluster-wide scan - for every candidate node.
+func (s *evenPodsSpreadScorer) ScoreWithMetadata(task *api.TaskInfo, node *api.NodeInfo, meta *PriorityMetadata) (int, error) {
+	return scoreTopologySpread(task, node, meta.TopologySpreadDomainCounts)
+}
+
+// NormalizeScores rescales every candidate node's raw weighted domain count (see scoreTopologySpread)
+// into the final 0..10 range, using the true maximum raw count across all of them as the denominator.
+// Computing that maximum once here - rather than letting each node bump it ad hoc for its own
+// self-placement - is what keeps scores comparable across nodes in the same task's scoring pass.
+func (s *evenPodsSpreadScorer) NormalizeScores(scores map[string]int) error {
+	var maxRaw int
+	for _, raw := range scores {
+		if raw > maxRaw {
+			maxRaw = raw
+		}
+	}
+	if maxRaw == 0 {
+		return nil
+	}
+
+	for name, raw := range scores {
+		scores[name] = 10 * (maxRaw - raw) / maxRaw
+	}
+	return nil
+}
+
+// topologySpreadConstraintCounts is the domain-count data for a single entry of a task's
+// TopologySpreadConstraints, aligned by index with task.Pod.Spec.TopologySpreadConstraints.
+type topologySpreadConstraintCounts struct {
+	counts map[string]int64
+}
+
+// PriorityMetadata holds per-task state that is expensive, or outright wrong, to rebuild once per
+// candidate node: InterPodAffinity and the evenpodsspread domain counts are whole-cluster computations
+// by nature, and the task's own resource requests and container images never change from one candidate
+// node to the next.
+type PriorityMetadata struct {
+	PodAffinityScores          schedulerapi.HostPriorityList
+	ResourceRequests           map[v1.ResourceName]int64
+	ContainerImages            []string
+	ImageNodeCounts            map[string]int
+	TotalNodes                 int
+	TopologySpreadDomainCounts []topologySpreadConstraintCounts
+}
+
+// MetadataAwareScorer is implemented by scorers that can use a task's PriorityMetadata instead of
+// recomputing cluster-wide state for every node. batchScorer prefers this path when a scorer supports
+// it and falls back to plain NodeScorer.Score otherwise.
+type MetadataAwareScorer interface {
+	NodeScorer
+	ScoreWithMetadata(task *api.TaskInfo, node *api.NodeInfo, meta *PriorityMetadata) (int, error)
+}
+
+// buildPriorityMetadata computes a task's PriorityMetadata once, up front.
+func buildPriorityMetadata(task *api.TaskInfo, cn *cachedNodeInfo, nl *util.NodeLister, pl *util.PodLister, nodeMap map[string]*cache.NodeInfo, nodeSlice []*v1.Node) (*PriorityMetadata, error) {
+	affinityScores, err := interPodAffinityScores(task, cn, nl, pl, nodeMap, nodeSlice)
+	if err != nil {
+		return nil, err
+	}
+
+	constraints := task.Pod.Spec.TopologySpreadConstraints
+	domainCounts := make([]topologySpreadConstraintCounts, len(constraints))
+	for i, constraint := range constraints {
+		selector, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid topology spread constraint selector: %v", err)
+		}
+		domainCounts[i] = topologySpreadConstraintCounts{counts: topologySpreadDomainCounts(nodeMap, constraint.TopologyKey, selector)}
+	}
+
+	return &PriorityMetadata{
+		PodAffinityScores: affinityScores,
+		ResourceRequests: map[v1.ResourceName]int64{
+			v1.ResourceCPU:    requestedResourceValue(task.Pod, v1.ResourceCPU),
+			v1.ResourceMemory: requestedResourceValue(task.Pod, v1.ResourceMemory),
+		},
+		ContainerImages:            containerImages(task.Pod),
+		ImageNodeCounts:            imageNodeCounts(nodeSlice),
+		TotalNodes:                 len(nodeSlice),
+		TopologySpreadDomainCounts: domainCounts,
+	}, nil
+}
+
+// batchScorer scores every candidate node for a task in one pass: it builds that task's
+// PriorityMetadata exactly once (so InterPodAffinity is computed a single time instead of once per
+// node), then fans the remaining per-node scoring out across a worker pool sized by GOMAXPROCS.
+// Results are cached per task so nodeOrderFn - still invoked once per (task, node) by
+// ssn.AddNodeOrderFn's existing contract - only triggers this work on the first node of each task.
+type batchScorer struct {
+	scorers       []NodeScorer
+	scorerWeights map[string]int
+	allNodes      []*api.NodeInfo
+
+	cachedNodeInfo *cachedNodeInfo
+	nodeLister     *util.NodeLister
+	podLister      *util.PodLister
+	nodeMap        map[string]*cache.NodeInfo
+	nodeSlice      []*v1.Node
+
+	mu    sync.Mutex
+	cache map[types.UID]map[string]float64
+}
+
+// scoresForTask returns every candidate node's total score for task, computing and caching it on the
+// first call for that task.
+func (b *batchScorer) scoresForTask(task *api.TaskInfo) (map[string]float64, error) {
+	b.mu.Lock()
+	scores, ok := b.cache[task.Pod.UID]
+	b.mu.Unlock()
+	if ok {
+		return scores, nil
+	}
+
+	scores, err := b.computeScores(task)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.cache[task.Pod.UID] = scores
+	b.mu.Unlock()
+
+	return scores, nil
+}
+
+// computeScores builds task's PriorityMetadata, scores b.allNodes concurrently across a worker pool
+// sized by GOMAXPROCS, lets each scorer normalize its own raw scores across every candidate node, then
+// aggregates the (possibly normalized) per-scorer scores into each node's weighted total.
+func (b *batchScorer) computeScores(task *api.TaskInfo) (map[string]float64, error) {
+	meta, err := buildPriorityMetadata(task, b.cachedNodeInfo, b.nodeLister, b.podLister, b.nodeMap, b.nodeSlice)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := b.rawScoresByScorer(task, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scorer := range b.scorers {
+		if err := scorer.NormalizeScores(raw[scorer.Name()]); err != nil {
+			return nil, fmt.Errorf("%s failed to normalize scores: %v", scorer.Name(), err)
+		}
+	}
+
+	scores := make(map[string]float64, len(b.allNodes))
+	for _, node := range b.allNodes {
+		var total float64
+		for _, scorer := range b.scorers {
+			weight := b.scorerWeights[scorer.Name()]
+			if weight == 0 {
+				continue
+			}
+			total += float64(raw[scorer.Name()][node.Name] * weight)
+		}
+		scores[node.Name] = total
+	}
+	return scores, nil
+}
+
+// rawScoresByScorer runs every scorer against every candidate node, fanned out across a worker pool
+// sized by GOMAXPROCS, and returns each scorer's raw (pre-weight, pre-normalize) score keyed by scorer
+// name then node name.
+func (b *batchScorer) rawScoresByScorer(task *api.TaskInfo, meta *PriorityMetadata) (map[string]map[string]int, error) {
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(b.allNodes) {
+		workers = len(b.allNodes)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	nodeCh := make(chan *api.NodeInfo, len(b.allNodes))
+	for _, node := range b.allNodes {
+		nodeCh <- node
+	}
+	close(nodeCh)
+
+	raw := make(map[string]map[string]int, len(b.scorers))
+	for _, scorer := range b.scorers {
+		raw[scorer.Name()] = make(map[string]int, len(b.allNodes))
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for node := range nodeCh {
+				nodeScores, err := b.scoreNode(task, node, meta)
+
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				for name, score := range nodeScores {
+					raw[name][node.Name] = score
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return raw, nil
+}
+
+// scoreNode returns every scorer's raw score for (task, node), keyed by scorer name.
+func (b *batchScorer) scoreNode(task *api.TaskInfo, node *api.NodeInfo, meta *PriorityMetadata) (map[string]int, error) {
+	scores := make(map[string]int, len(b.scorers))
+	for _, scorer := range b.scorers {
+		weight := b.scorerWeights[scorer.Name()]
+		if weight == 0 {
+			continue
+		}
+
+		var raw int
+		var err error
+		if aware, ok := scorer.(MetadataAwareScorer); ok {
+			raw, err = aware.ScoreWithMetadata(task, node, meta)
+		} else {
+			raw, err = scorer.Score(task, node)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s score failed for node %s: %v", scorer.Name(), node.Name, err)
+		}
+
+		scores[scorer.Name()] = raw
+	}
+	return scores, nil
 }
 
 func (pp *nodeOrderPlugin) OnSessionOpen(ssn *framework.Session) {
 	var nodeMap map[string]*cache.NodeInfo
 	var nodeSlice []*v1.Node
 
-	weight := calculateWeight(pp.pluginArguments)
-
 	pl := util.NewPodLister(ssn)
 
 	nl := &util.NodeLister{
@@ -185,55 +1039,63 @@ func (pp *nodeOrderPlugin) OnSessionOpen(ssn *framework.Session) {
 		},
 	})
 
-	nodeOrderFn := func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
-		var interPodAffinityScore schedulerapi.HostPriorityList
+	ctx := &ScorerContext{
+		Arguments:      pp.pluginArguments,
+		NodeMap:        nodeMap,
+		NodeSlice:      nodeSlice,
+		PodLister:      pl,
+		NodeLister:     nl,
+		CachedNodeInfo: cn,
+	}
 
-		nodeInfo, found := nodeMap[node.Name]
-		if !found {
-			nodeInfo = cache.NewNodeInfo(node.Pods()...)
-			nodeInfo.SetNode(node.Node)
-			glog.Warningf("node order, generate node info for %s at NodeOrderFn is unexpected", node.Name)
-		}
-		var score = 0.0
+	// Build the scorer list once per session, in a stable order so weighting and logs are
+	// reproducible. Each scorer's weight comes from its own "<name>.weight" argument.
+	names := make([]string, 0, len(nodeScorerFactories))
+	for name := range nodeScorerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-		//TODO: Add ImageLocalityPriority Function once priorityMetadata is published
-		//Issue: #74132 in kubernetes ( https://github.com/kubernetes/kubernetes/issues/74132 )
+	scorers := make([]NodeScorer, 0, len(names))
+	scorerWeights := make(map[string]int, len(names))
+	for _, name := range names {
+		scorer := nodeScorerFactories[name](ctx)
 
-		host, err := priorities.LeastRequestedPriorityMap(task.Pod, nil, nodeInfo)
-		if err != nil {
-			glog.Warningf("Least Requested Priority Failed because of Error: %v", err)
-			return 0, err
-		}
-		// If leastReqWeight in provided, host.Score is multiplied with weight, if not, host.Score is added to total score.
-		score = score + float64(host.Score*weight.leastReqWeight)
+		scorerWeight := defaultNodeScorerWeight(name)
+		pp.pluginArguments.GetInt(&scorerWeight, nodeScorerWeightArg(name))
 
-		host, err = priorities.BalancedResourceAllocationMap(task.Pod, nil, nodeInfo)
-		if err != nil {
-			glog.Warningf("Balanced Resource Allocation Priority Failed because of Error: %v", err)
-			return 0, err
-		}
-		// If balancedRescourceWeight in provided, host.Score is multiplied with weight, if not, host.Score is added to total score.
-		score = score + float64(host.Score*weight.balancedRescourceWeight)
+		scorers = append(scorers, scorer)
+		scorerWeights[scorer.Name()] = scorerWeight
+	}
 
-		host, err = priorities.CalculateNodeAffinityPriorityMap(task.Pod, nil, nodeInfo)
-		if err != nil {
-			glog.Warningf("Calculate Node Affinity Priority Failed because of Error: %v", err)
-			return 0, err
-		}
-		// If nodeAffinityWeight in provided, host.Score is multiplied with weight, if not, host.Score is added to total score.
-		score = score + float64(host.Score*weight.nodeAffinityWeight)
+	allNodes := make([]*api.NodeInfo, 0, len(ssn.Nodes))
+	for _, node := range ssn.Nodes {
+		allNodes = append(allNodes, node)
+	}
+
+	batch := &batchScorer{
+		scorers:        scorers,
+		scorerWeights:  scorerWeights,
+		allNodes:       allNodes,
+		cachedNodeInfo: cn,
+		nodeLister:     nl,
+		podLister:      pl,
+		nodeMap:        nodeMap,
+		nodeSlice:      nodeSlice,
+		cache:          map[types.UID]map[string]float64{},
+	}
 
-		mapFn := priorities.NewInterPodAffinityPriority(cn, nl, pl, v1.DefaultHardPodAffinitySymmetricWeight)
-		interPodAffinityScore, err = mapFn(task.Pod, nodeMap, nodeSlice)
+	// nodeOrderFn keeps ssn.AddNodeOrderFn's existing one-call-per-(task, node) contract, but is now a
+	// thin wrapper around batch: the first node scored for a given task triggers scoring for every
+	// node at once, and every later node for that task is a cache lookup.
+	nodeOrderFn := func(task *api.TaskInfo, node *api.NodeInfo) (float64, error) {
+		scores, err := batch.scoresForTask(task)
 		if err != nil {
-			glog.Warningf("Calculate Inter Pod Affinity Priority Failed because of Error: %v", err)
 			return 0, err
 		}
-		hostScore := getInterPodAffinityScore(node.Name, interPodAffinityScore)
-		// If podAffinityWeight in provided, host.Score is multiplied with weight, if not, host.Score is added to total score.
-		score = score + float64(hostScore*weight.podAffinityWeight)
 
-		glog.V(4).Infof("Total Score for that node is: %d", score)
+		score := scores[node.Name]
+		glog.V(4).Infof("Total Score for that node is: %v", score)
 		return score, nil
 	}
 	ssn.AddNodeOrderFn(pp.Name(), nodeOrderFn)
@@ -0,0 +1,177 @@
+/*
+Copyright 2019 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodeorder
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/scheduler/cache"
+
+	"github.com/kubernetes-sigs/kube-batch/pkg/scheduler/api"
+)
+
+func TestFunctionShapeScore(t *testing.T) {
+	tests := []struct {
+		name        string
+		shape       functionShape
+		utilization int64
+		want        int64
+	}{
+		{
+			name:        "bin-pack shape interpolates midpoint",
+			shape:       functionShape{{utilization: 0, score: 0}, {utilization: 100, score: 10}},
+			utilization: 50,
+			want:        5,
+		},
+		{
+			name:        "bin-pack shape clamps above last point",
+			shape:       functionShape{{utilization: 0, score: 0}, {utilization: 100, score: 10}},
+			utilization: 150,
+			want:        10,
+		},
+		{
+			// The chunk0-1 fix (98ac579) re-enabled decreasing-score shapes so spread policies
+			// like this one are configurable.
+			name:        "spread shape decreases with utilization",
+			shape:       functionShape{{utilization: 0, score: 10}, {utilization: 100, score: 0}},
+			utilization: 50,
+			want:        5,
+		},
+		{
+			name:        "three-point shape interpolates within the second segment",
+			shape:       functionShape{{utilization: 0, score: 0}, {utilization: 50, score: 2}, {utilization: 100, score: 10}},
+			utilization: 75,
+			want:        6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.shape.score(tt.utilization); got != tt.want {
+				t.Errorf("score(%d) = %d, want %d", tt.utilization, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImageLocalityHostScore(t *testing.T) {
+	const (
+		minThreshold = imageLocalityMinThreshold
+		maxThreshold = imageLocalityMaxThreshold
+	)
+
+	tests := []struct {
+		name   string
+		spread int64
+		want   int64
+	}{
+		{name: "below min threshold scores zero", spread: minThreshold - 1, want: 0},
+		{name: "at max threshold scores max", spread: maxThreshold, want: 10},
+		{name: "above max threshold clamps to max", spread: maxThreshold * 2, want: 10},
+		{name: "midpoint interpolates to half the max score", spread: (minThreshold + maxThreshold) / 2, want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := imageLocalityHostScore(tt.spread); got != tt.want {
+				t.Errorf("imageLocalityHostScore(%d) = %d, want %d", tt.spread, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestedToCapacityRatioScore(t *testing.T) {
+	node := &v1.Node{
+		Status: v1.NodeStatus{
+			Allocatable: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("4"),
+				v1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		},
+	}
+	nodeInfo := cache.NewNodeInfo()
+	if err := nodeInfo.SetNode(node); err != nil {
+		t.Fatalf("SetNode failed: %v", err)
+	}
+
+	task := &api.TaskInfo{Pod: &v1.Pod{}}
+	resources := resourceToWeightMap{v1.ResourceCPU: 1, v1.ResourceMemory: 1}
+	shape := functionShape{{utilization: 0, score: 0}, {utilization: 100, score: 10}}
+
+	// Half of both cpu and memory allocatable, supplied directly so requestedPlusNodeResourceValue
+	// never has to fall back to walking task.Pod's containers.
+	podRequests := map[v1.ResourceName]int64{
+		v1.ResourceCPU:    2000,
+		v1.ResourceMemory: 4 * 1024 * 1024 * 1024,
+	}
+
+	got := requestedToCapacityRatioScore(task, nodeInfo, podRequests, resources, shape)
+	if want := 5.0; got != want {
+		t.Errorf("requestedToCapacityRatioScore() = %v, want %v", got, want)
+	}
+}
+
+func TestScoreTopologySpread(t *testing.T) {
+	task := &api.TaskInfo{
+		Pod: &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "x"}},
+			Spec: v1.PodSpec{
+				TopologySpreadConstraints: []v1.TopologySpreadConstraint{
+					{
+						MaxSkew:       1,
+						TopologyKey:   "zone",
+						LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "x"}},
+					},
+				},
+			},
+		},
+	}
+	node := &api.NodeInfo{
+		Name: "n1",
+		Node: &v1.Node{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"zone": "z1"}}},
+	}
+	domainCounts := []topologySpreadConstraintCounts{{counts: map[string]int64{"z1": 4, "z2": 2}}}
+
+	got, err := scoreTopologySpread(task, node, domainCounts)
+	if err != nil {
+		t.Fatalf("scoreTopologySpread returned error: %v", err)
+	}
+	// z1 already has 4 matching pods, plus the task itself landing there: weighted count is 5.
+	if want := 5; got != want {
+		t.Errorf("scoreTopologySpread() = %d, want %d", got, want)
+	}
+}
+
+func TestEvenPodsSpreadScorerNormalizeScores(t *testing.T) {
+	s := &evenPodsSpreadScorer{}
+
+	// Regression test for the bug fixed in 205a7ba: a node whose self-placement only ties the
+	// cluster's pre-existing max domain count (4 -> 5) must score strictly higher than a node whose
+	// self-placement pushes an already-max domain past it (5 -> 6), because the former leaves the
+	// cluster less skewed. Both raw counts are pre-normalize weighted domain counts from
+	// scoreTopologySpread.
+	scores := map[string]int{"exceedsMax": 6, "tiesMax": 5}
+	if err := s.NormalizeScores(scores); err != nil {
+		t.Fatalf("NormalizeScores returned error: %v", err)
+	}
+	if scores["tiesMax"] <= scores["exceedsMax"] {
+		t.Errorf("expected tiesMax score (%d) to exceed exceedsMax score (%d)", scores["tiesMax"], scores["exceedsMax"])
+	}
+}